@@ -0,0 +1,154 @@
+// Package archive fetches a forge's archive endpoint (e.g.
+// `/org/repo/archive/<ref>.tar.gz`) and unpacks it directly into a
+// directory, as a faster alternative to a full git clone for forges that
+// support it.
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nektos/act/pkg/common"
+)
+
+// ErrNotFound is returned when the forge has no archive for the requested
+// ref (typically a 404), so the caller should fall back to a git clone.
+var ErrNotFound = errors.New("archive not found")
+
+// FetchTarballInput describes a single archive download.
+type FetchTarballInput struct {
+	URL   string
+	Token string
+	Dir   string
+}
+
+// FetchTarball downloads a .tar.gz from URL and extracts it into Dir,
+// stripping the single top-level directory forges wrap archives in
+// (`<repo>-<ref>/...`), mirroring what `git clone` would have produced.
+func FetchTarball(input FetchTarballInput) common.Executor {
+	return func(ctx context.Context) error {
+		logger := common.Logger(ctx)
+		logger.Debugf("fetching action archive '%s'", input.URL)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, input.URL, nil)
+		if err != nil {
+			return err
+		}
+		if input.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+input.Token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound {
+			return ErrNotFound
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("fetching action archive '%s': unexpected status %s", input.URL, resp.Status)
+		}
+
+		gzr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return err
+		}
+		defer gzr.Close()
+
+		return untar(gzr, input.Dir)
+	}
+}
+
+// untar extracts the tar stream r into dir, dropping the first path segment of every
+// entry (the `<repo>-<ref>/` wrapper directory forges add to archives).
+func untar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := stripTopLevelDir(header.Name)
+		if name == "" {
+			continue
+		}
+		target := filepath.Join(dir, name)
+		if !isWithinDir(dir, target) {
+			return fmt.Errorf("archive entry '%s' escapes destination directory", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(header.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			f.Close()
+		case tar.TypeSymlink:
+			// header.Linkname is stored verbatim as the symlink's target,
+			// so an absolute Linkname (e.g. "/etc/passwd") must be rejected
+			// outright - filepath.Join would report it as "within dir" (it
+			// discards the absolute path's leading separator when joining),
+			// while os.Symlink still creates a link that really does point
+			// outside dir.
+			if filepath.IsAbs(header.Linkname) {
+				return fmt.Errorf("archive entry '%s' has an absolute link target %q", header.Name, header.Linkname)
+			}
+			if !isWithinDir(dir, filepath.Join(filepath.Dir(target), header.Linkname)) {
+				return fmt.Errorf("archive entry '%s' links outside destination directory", header.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// isWithinDir reports whether target (after cleaning) is dir itself or a
+// descendant of it, guarding against tar entries using `..` or absolute
+// paths to escape the extraction directory ("tar-slip").
+func isWithinDir(dir, target string) bool {
+	dir = filepath.Clean(dir)
+	target = filepath.Clean(target)
+	if target == dir {
+		return true
+	}
+	return strings.HasPrefix(target, dir+string(filepath.Separator))
+}
+
+func stripTopLevelDir(name string) string {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[1]
+}
@@ -0,0 +1,123 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTar(t *testing.T, entries []tar.Header, body string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i := range entries {
+		h := entries[i]
+		if h.Typeflag == tar.TypeReg {
+			h.Size = int64(len(body))
+		}
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestUntarRegularFile(t *testing.T) {
+	dir := t.TempDir()
+	buf := writeTar(t, []tar.Header{
+		{Name: "repo-main/sub/file.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+	}, "hello")
+
+	if err := untar(buf, dir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "sub", "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("got %q, want %q", data, "hello")
+	}
+}
+
+func TestUntarRejectsPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	buf := writeTar(t, []tar.Header{
+		{Name: "repo-main/../../etc/passwd", Typeflag: tar.TypeReg, Mode: 0o644},
+	}, "pwned")
+
+	if err := untar(buf, dir); err == nil {
+		t.Fatal("expected an error for a path-traversal entry, got nil")
+	}
+}
+
+func TestUntarRejectsAbsoluteSymlinkTarget(t *testing.T) {
+	dir := t.TempDir()
+	buf := writeTar(t, []tar.Header{
+		{Name: "repo-main/evil", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd", Mode: 0o777},
+	}, "")
+
+	if err := untar(buf, dir); err == nil {
+		t.Fatal("expected an error for an absolute symlink target, got nil")
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "evil")); !os.IsNotExist(err) {
+		t.Errorf("expected no symlink to be created, stat err = %v", err)
+	}
+}
+
+func TestUntarRejectsSymlinkEscapingDir(t *testing.T) {
+	dir := t.TempDir()
+	buf := writeTar(t, []tar.Header{
+		{Name: "repo-main/evil", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd", Mode: 0o777},
+	}, "")
+
+	if err := untar(buf, dir); err == nil {
+		t.Fatal("expected an error for a relative symlink target escaping dir, got nil")
+	}
+}
+
+func TestUntarAllowsSymlinkWithinDir(t *testing.T) {
+	dir := t.TempDir()
+	buf := writeTar(t, []tar.Header{
+		{Name: "repo-main/target.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "repo-main/link", Typeflag: tar.TypeSymlink, Linkname: "target.txt", Mode: 0o777},
+	}, "hi")
+
+	if err := untar(buf, dir); err != nil {
+		t.Fatal(err)
+	}
+	target, err := os.Readlink(filepath.Join(dir, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "target.txt" {
+		t.Errorf("got link target %q, want %q", target, "target.txt")
+	}
+}
+
+func TestIsWithinDir(t *testing.T) {
+	tests := []struct {
+		dir, target string
+		want        bool
+	}{
+		{"/tmp/extract", "/tmp/extract", true},
+		{"/tmp/extract", "/tmp/extract/sub/file", true},
+		{"/tmp/extract", "/tmp/extract-evil/file", false},
+		{"/tmp/extract", "/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		if got := isWithinDir(tt.dir, tt.target); got != tt.want {
+			t.Errorf("isWithinDir(%q, %q) = %v, want %v", tt.dir, tt.target, got, tt.want)
+		}
+	}
+}
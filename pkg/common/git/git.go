@@ -0,0 +1,280 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/nektos/act/pkg/common"
+)
+
+// Error wraps a git command failure, optionally carrying the resolved commit
+// SHA so callers can build a more actionable message (see ErrShortRef).
+type Error struct {
+	err    error
+	commit string
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+func (e *Error) Commit() string {
+	return e.commit
+}
+
+// ErrShortRef reports a ref that is a shortened commit SHA. NewGitCloneExecutor
+// does not return it: a short SHA that fails the shallow `--branch` clone is
+// retried with a full clone and checked out directly.
+var ErrShortRef = errors.New("short SHA refs are not supported, please use the full commit SHA")
+
+// NewGitCloneExecutorInput describes a single clone operation.
+type NewGitCloneExecutorInput struct {
+	URL   string
+	Ref   string
+	Dir   string
+	Token string
+
+	// CloneDepth limits how much history is fetched. A value <= 0 means a
+	// full clone. When Ref is a named branch/tag, this is passed straight
+	// through to `git clone --depth`. When Ref looks like a full commit
+	// SHA, a depth-1 clone is done first and the SHA is fetched
+	// separately, since `git clone --depth` doesn't accept a SHA as the
+	// branch to clone.
+	CloneDepth int
+
+	// Recursive also clones submodules. SubmoduleDepth, if > 0, is passed
+	// to `git submodule update --depth` so submodules can be shallowed
+	// independently of the outer repo. SubmoduleRecursive controls whether
+	// submodules-of-submodules are also fetched (GitHub's `with.submodules:
+	// recursive`); when false, only the top-level submodules are checked
+	// out (`with.submodules: true`).
+	Recursive          bool
+	SubmoduleRecursive bool
+	SubmoduleDepth     int
+
+	// LFS pulls Git LFS objects after checkout, for actions that ship
+	// large binary fixtures.
+	LFS bool
+
+	// Sparse, if non-empty, is a list of `git sparse-checkout set`
+	// patterns. Only matching paths are populated in the working tree,
+	// which is handy for actions that live in a subdirectory of a large
+	// monorepo-style action repo.
+	Sparse []string
+}
+
+// isFullSHA reports whether ref looks like a full (40 or 64 hex char) commit
+// SHA, as opposed to a branch or tag name.
+func isFullSHA(ref string) bool {
+	if len(ref) != 40 && len(ref) != 64 {
+		return false
+	}
+	for _, c := range ref {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFullSHA reports whether ref is already a full commit SHA, i.e. it's
+// immutable and safe to cache forever.
+func IsFullSHA(ref string) bool {
+	return isFullSHA(ref)
+}
+
+// ResolveRef asks the remote what commit ref currently points at, without
+// cloning anything, so callers can check a content-addressable cache before
+// paying for a clone.
+func ResolveRef(ctx context.Context, url, ref, token string) (string, error) {
+	if isFullSHA(ref) {
+		return ref, nil
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", url, ref)
+	cmd.Env = gitAuthEnv(url, token)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", &Error{err: fmt.Errorf("git ls-remote %s %s: %w", url, ref, err)}
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("ref %q not found on %s", ref, url)
+	}
+	// An annotated tag is listed twice: as the tag object, and suffixed with
+	// "^{}" as the commit it peels to.
+	for i := 1; i < len(fields); i += 2 {
+		if strings.HasSuffix(fields[i], "^{}") {
+			return fields[i-1], nil
+		}
+	}
+	return fields[0], nil
+}
+
+// NewGitCloneExecutor creates an executor that materializes URL@Ref into Dir.
+func NewGitCloneExecutor(input NewGitCloneExecutorInput) common.Executor {
+	return func(ctx context.Context) error {
+		logger := common.Logger(ctx)
+		logger.Debugf("git clone '%s' ref '%s' depth %d", input.URL, input.Ref, input.CloneDepth)
+
+		var err error
+		if input.CloneDepth > 0 && isFullSHA(input.Ref) {
+			err = cloneShallowThenFetchSHA(ctx, input)
+		} else {
+			err = cloneShallowByRef(ctx, input)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(input.Sparse) > 0 {
+			args := append([]string{"sparse-checkout", "set"}, input.Sparse...)
+			if err := runGit(ctx, input.Dir, input.URL, input.Token, args...); err != nil {
+				return err
+			}
+		}
+
+		if input.Recursive {
+			if err := updateSubmodules(ctx, input); err != nil {
+				return err
+			}
+		}
+
+		if input.LFS {
+			if err := runGit(ctx, input.Dir, input.URL, input.Token, "lfs", "pull"); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// updateSubmodules initializes and fetches submodules, forwarding the same
+// Token used for the outer clone so private submodules on the same forge
+// keep working. The Authorization header this installs is scoped to the
+// outer clone's host (see gitAuthEnv), so it's only ever sent for
+// submodules hosted on that same forge - a submodule's `.gitmodules` URL
+// pointing at an unrelated host never sees the token.
+func updateSubmodules(ctx context.Context, input NewGitCloneExecutorInput) error {
+	args := []string{"submodule", "update", "--init"}
+	if input.SubmoduleRecursive {
+		args = append(args, "--recursive")
+	}
+	if input.SubmoduleDepth > 0 {
+		args = append(args, "--depth", fmt.Sprintf("%d", input.SubmoduleDepth))
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = input.Dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = gitAuthEnv(input.URL, input.Token)
+	if err := cmd.Run(); err != nil {
+		return &Error{err: fmt.Errorf("git %s: %w", strings.Join(args, " "), err)}
+	}
+	return nil
+}
+
+// cloneShallowByRef clones straight from the remote, passing --branch when
+// the ref is a named branch/tag and a depth limit is configured. Without a
+// depth limit it does a full clone and checks out the ref.
+func cloneShallowByRef(ctx context.Context, input NewGitCloneExecutorInput) error {
+	if input.CloneDepth <= 0 {
+		return fullCloneAndCheckout(ctx, input)
+	}
+	args := []string{"clone", "--depth", fmt.Sprintf("%d", input.CloneDepth), "--branch", input.Ref, input.URL, input.Dir}
+
+	if err := runGit(ctx, "", input.URL, input.Token, args...); err != nil {
+		// The ref might not be a branch/tag after all (e.g. a short
+		// SHA slipped through isFullSHA) - retry with a full clone
+		// rather than failing outright.
+		return fullCloneAndCheckout(ctx, input)
+	}
+	return nil
+}
+
+// cloneShallowThenFetchSHA does a depth-1 clone of the default branch, then
+// fetches the requested SHA directly. This relies on the server advertising
+// `uploadpack.allowReachableSHA1InWant` (git protocol v2); servers that
+// refuse the fetch are handled by falling back to a full clone.
+func cloneShallowThenFetchSHA(ctx context.Context, input NewGitCloneExecutorInput) error {
+	if err := runGit(ctx, "", input.URL, input.Token, "clone", "--depth", "1", "--no-checkout", input.URL, input.Dir); err != nil {
+		return err
+	}
+
+	if err := runGit(ctx, input.Dir, input.URL, input.Token, "fetch", "--depth", "1", "origin", input.Ref); err != nil {
+		// Server doesn't allow fetching arbitrary SHAs - fall back to a
+		// full clone of the ref.
+		if rmErr := os.RemoveAll(input.Dir); rmErr != nil {
+			return rmErr
+		}
+		return fullCloneAndCheckout(ctx, input)
+	}
+
+	if err := runGit(ctx, input.Dir, input.URL, input.Token, "checkout", "FETCH_HEAD"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func fullCloneAndCheckout(ctx context.Context, input NewGitCloneExecutorInput) error {
+	if err := runGit(ctx, "", input.URL, input.Token, "clone", input.URL, input.Dir); err != nil {
+		return err
+	}
+	return runGit(ctx, input.Dir, input.URL, input.Token, "checkout", input.Ref)
+}
+
+// gitAuthEnv returns the process environment with an extra `git -c
+// http.<scheme>://<host>/.extraHeader=Authorization: Bearer <token>`
+// equivalent set via GIT_CONFIG_*. Scoping the key to cloneURL's host
+// (rather than the bare `http.extraHeader`) means the token is only ever
+// attached to requests against that host - e.g. a submodule whose
+// `.gitmodules` URL points at an unrelated forge never receives it. It
+// also keeps the token out of the URL itself (and therefore out of `ps`,
+// shell history, or /proc/<pid>/cmdline, unlike embedding it as userinfo).
+func gitAuthEnv(cloneURL, token string) []string {
+	env := os.Environ()
+	if token == "" {
+		return env
+	}
+	scope := hostScope(cloneURL)
+	if scope == "" {
+		return env
+	}
+	return append(env, "GIT_CONFIG_COUNT=1",
+		fmt.Sprintf("GIT_CONFIG_KEY_0=http.%s.extraHeader", scope),
+		fmt.Sprintf("GIT_CONFIG_VALUE_0=Authorization: Bearer %s", token))
+}
+
+// hostScope returns the scheme://host[:port]/ prefix of rawURL, or "" if it
+// can't be parsed as an absolute URL.
+func hostScope(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/"
+}
+
+func runGit(ctx context.Context, dir, cloneURL, token string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = gitAuthEnv(cloneURL, token)
+	if err := cmd.Run(); err != nil {
+		return &Error{err: fmt.Errorf("git %s: %w", strings.Join(args, " "), err)}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package git
+
+import "testing"
+
+func TestGitAuthEnvNoToken(t *testing.T) {
+	env := gitAuthEnv("https://example.net/org/repo", "")
+	for _, kv := range env {
+		if kv == "GIT_CONFIG_COUNT=1" {
+			t.Fatalf("expected no GIT_CONFIG_COUNT entry without a token, got %v", env)
+		}
+	}
+}
+
+func TestGitAuthEnvScopesHeaderToHost(t *testing.T) {
+	env := gitAuthEnv("https://example.net/org/repo", "secret")
+	want := map[string]bool{
+		"GIT_CONFIG_COUNT=1": false,
+		"GIT_CONFIG_KEY_0=http.https://example.net/.extraHeader": false,
+		"GIT_CONFIG_VALUE_0=Authorization: Bearer secret":        false,
+	}
+	for _, kv := range env {
+		if _, ok := want[kv]; ok {
+			want[kv] = true
+		}
+	}
+	for kv, found := range want {
+		if !found {
+			t.Errorf("expected env to contain %q, got %v", kv, env)
+		}
+	}
+}
+
+func TestGitAuthEnvUnparsableURL(t *testing.T) {
+	env := gitAuthEnv(":not-a-url", "secret")
+	for _, kv := range env {
+		if kv == "GIT_CONFIG_COUNT=1" {
+			t.Fatalf("expected no auth header for an unparsable URL, got %v", env)
+		}
+	}
+}
+
+func TestHostScope(t *testing.T) {
+	tests := map[string]string{
+		"https://example.net/org/repo": "https://example.net/",
+		"http://example.net:3000/org":  "http://example.net:3000/",
+		":not-a-url":                   "",
+		"":                             "",
+	}
+	for in, want := range tests {
+		if got := hostScope(in); got != want {
+			t.Errorf("hostScope(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestIsFullSHA(t *testing.T) {
+	tests := map[string]bool{
+		"0123456789abcdef0123456789abcdef01234567": true,
+		"main":   false,
+		"v1.0":   false,
+		"abc123": false,
+		"":       false,
+	}
+	for ref, want := range tests {
+		if got := IsFullSHA(ref); got != want {
+			t.Errorf("IsFullSHA(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
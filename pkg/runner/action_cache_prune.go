@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PruneActionCache removes blob directories under <cacheDir>/blobs that no
+// ref in <cacheDir>/refs currently points to.
+//
+// This is meant to back a `--prune-action-cache` CLI subcommand, but this
+// tree has no cmd/main entry point to wire a flag into, so for now it's
+// exported for callers (e.g. a future CLI layer, or ad-hoc use from a test)
+// to invoke directly.
+func PruneActionCache(cacheDir string) ([]string, error) {
+	liveSHAs := map[string]bool{}
+	refsDir := filepath.Join(cacheDir, "refs")
+	refEntries, err := os.ReadDir(refsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range refEntries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(refsDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		liveSHAs[strings.TrimSpace(string(data))] = true
+	}
+
+	blobsDir := filepath.Join(cacheDir, "blobs")
+	blobEntries, err := os.ReadDir(blobsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var pruned []string
+	for _, entry := range blobEntries {
+		if liveSHAs[entry.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(blobsDir, entry.Name())); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, entry.Name())
+	}
+	return pruned, nil
+}
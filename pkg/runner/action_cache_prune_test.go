@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestPruneActionCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	refsDir := filepath.Join(cacheDir, "refs")
+	blobsDir := filepath.Join(cacheDir, "blobs")
+	if err := os.MkdirAll(refsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(blobsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, blob := range []string{"live-sha", "stale-sha"} {
+		if err := os.MkdirAll(filepath.Join(blobsDir, blob), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(refsDir, "some-action"), []byte("live-sha"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := PruneActionCache(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(pruned)
+	if len(pruned) != 1 || pruned[0] != "stale-sha" {
+		t.Fatalf("pruned = %v, want [stale-sha]", pruned)
+	}
+
+	if _, err := os.Stat(filepath.Join(blobsDir, "live-sha")); err != nil {
+		t.Errorf("live-sha blob should still exist: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(blobsDir, "stale-sha")); !os.IsNotExist(err) {
+		t.Errorf("stale-sha blob should have been removed, stat err = %v", err)
+	}
+}
+
+func TestPruneActionCacheNoBlobsDir(t *testing.T) {
+	pruned, err := PruneActionCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != nil {
+		t.Fatalf("pruned = %v, want nil", pruned)
+	}
+}
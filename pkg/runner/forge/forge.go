@@ -0,0 +1,121 @@
+// Package forge lets act talk to self-hosted git forges (Gitea, GHES,
+// nested-path installs, ...) without hardcoding their hostnames or token
+// env vars into the runner.
+package forge
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// URLLayout describes how an action's `uses:` string maps to org/repo/path
+// once the host prefix has been stripped.
+type URLLayout string
+
+const (
+	LayoutGitHub URLLayout = "github"
+	LayoutGitea  URLLayout = "gitea"
+	LayoutNested URLLayout = "nested"
+)
+
+// Entry is one forge a user has told act about.
+type Entry struct {
+	HostPattern string    `yaml:"host" json:"host"`
+	URLLayout   URLLayout `yaml:"layout" json:"layout"`
+	TokenEnv    string    `yaml:"tokenEnv" json:"tokenEnv"`
+
+	// PathDepth is how many path segments after the host belong to the
+	// forge's URL layout (e.g. a Gitea instance nesting actions under
+	// `/git/`) rather than the org/repo themselves. It's only consulted
+	// for LayoutNested; LayoutGitHub and LayoutGitea put org/repo
+	// directly after the host, so it's ignored for those.
+	PathDepth int `yaml:"pathDepth" json:"pathDepth"`
+
+	// ArchiveURLTemplate, if set, is a string with literal
+	// {host} (the clone URL up to the org, e.g. "https://git.example.net",
+	// including any nested path prefix), {org}, {repo} and {ref} placeholders pointing at the forge's tarball
+	// endpoint (e.g. "{host}/{org}/{repo}/archive/{ref}.tar.gz"). When
+	// empty, the forge is assumed not to support archive fetches.
+	ArchiveURLTemplate string `yaml:"archiveURLTemplate" json:"archiveURLTemplate"`
+}
+
+// ArchiveURL renders ArchiveURLTemplate for the given org/repo/ref, or
+// returns "" if this entry doesn't support archive fetches.
+func (e Entry) ArchiveURL(host, org, repo, ref string) string {
+	if e.ArchiveURLTemplate == "" {
+		return ""
+	}
+	r := strings.NewReplacer("{host}", host, "{org}", org, "{repo}", repo, "{ref}", ref)
+	return r.Replace(e.ArchiveURLTemplate)
+}
+
+// Registry resolves a cloned action's host prefix to the forge entry that
+// describes it.
+type Registry struct {
+	entries []Entry
+}
+
+// NewRegistry wraps a pre-loaded set of entries, in priority order.
+func NewRegistry(entries []Entry) *Registry {
+	return &Registry{entries: entries}
+}
+
+// Load reads forge entries from a YAML or JSON config file (decided by
+// extension, defaulting to YAML) and merges in any entries set via the
+// ACT_FORGES env var (a JSON array, for shell-friendly overrides).
+func Load(configPath string) (*Registry, error) {
+	var entries []Entry
+
+	if configPath != "" {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(configPath, ".json") {
+			if err := json.Unmarshal(data, &entries); err != nil {
+				return nil, err
+			}
+		} else if err := yaml.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw := os.Getenv("ACT_FORGES"); raw != "" {
+		var envEntries []Entry
+		if err := json.Unmarshal([]byte(raw), &envEntries); err != nil {
+			return nil, err
+		}
+		entries = append(entries, envEntries...)
+	}
+
+	return NewRegistry(entries), nil
+}
+
+// Lookup finds the entry whose HostPattern is a prefix of hostAndPath. Any
+// "http://" or "https://" schema is stripped first, so callers can pass
+// either a bare "host/org/repo" or a full clone URL.
+func (r *Registry) Lookup(hostAndPath string) (Entry, bool) {
+	if r == nil {
+		return Entry{}, false
+	}
+	hostAndPath = strings.TrimPrefix(strings.TrimPrefix(hostAndPath, "https://"), "http://")
+	for _, e := range r.entries {
+		if strings.HasPrefix(hostAndPath, e.HostPattern) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Token returns the token configured for the forge that owns hostAndPath,
+// or "" if none matched or no TokenEnv was set.
+func (r *Registry) Token(hostAndPath string) string {
+	entry, ok := r.Lookup(hostAndPath)
+	if !ok || entry.TokenEnv == "" {
+		return ""
+	}
+	return os.Getenv(entry.TokenEnv)
+}
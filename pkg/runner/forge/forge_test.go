@@ -0,0 +1,38 @@
+package forge
+
+import "testing"
+
+func TestRegistryLookupNormalizesSchema(t *testing.T) {
+	r := NewRegistry([]Entry{
+		{HostPattern: "git.example.net", URLLayout: LayoutNested, PathDepth: 1},
+	})
+
+	for _, hostAndPath := range []string{
+		"git.example.net/git/org/repo",
+		"https://git.example.net/git/org/repo",
+		"http://git.example.net/git/org/repo",
+	} {
+		if _, ok := r.Lookup(hostAndPath); !ok {
+			t.Errorf("Lookup(%q) did not match HostPattern %q", hostAndPath, "git.example.net")
+		}
+	}
+}
+
+func TestRegistryLookupNoMatch(t *testing.T) {
+	r := NewRegistry([]Entry{{HostPattern: "git.example.net"}})
+
+	if _, ok := r.Lookup("https://github.com/org/repo"); ok {
+		t.Error("Lookup matched a host that isn't configured")
+	}
+}
+
+func TestRegistryTokenUsesSameNormalization(t *testing.T) {
+	t.Setenv("TEST_FORGE_TOKEN", "secret")
+	r := NewRegistry([]Entry{
+		{HostPattern: "git.example.net", TokenEnv: "TEST_FORGE_TOKEN"},
+	})
+
+	if got := r.Token("https://git.example.net/org/repo"); got != "secret" {
+		t.Errorf("Token() = %q, want %q", got, "secret")
+	}
+}
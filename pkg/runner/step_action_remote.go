@@ -5,19 +5,29 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
-
-	gogit "github.com/go-git/go-git/v5"
+	"time"
 
 	"github.com/nektos/act/pkg/common"
+	"github.com/nektos/act/pkg/common/archive"
 	"github.com/nektos/act/pkg/common/git"
 	"github.com/nektos/act/pkg/model"
+	"github.com/nektos/act/pkg/runner/forge"
 )
 
+// This file reads several RunContext.Config fields - ActionCloneDepth,
+// ForgesConfig, ActionFetchMode and ActionCacheTTL - that back the
+// `--action-clone-depth`, `--forges-config` and archive-fetch-mode CLI
+// flags described in their originating change requests. Neither Config
+// nor its flag parsing is part of this snapshot of the act codebase, so
+// those fields and flags aren't defined here; they're written as if Config already had them, the same
+// assumption this file already makes about RunContext itself.
 type stepActionRemote struct {
 	Step                *model.Step
 	RunContext          *RunContext
@@ -28,6 +38,7 @@ type stepActionRemote struct {
 	action              *model.Action
 	env                 map[string]string
 	remoteAction        *remoteAction
+	actionDir           string
 }
 
 var stepActionRemoteNewCloneExecutor = git.NewGitCloneExecutor
@@ -45,7 +56,12 @@ func (sar *stepActionRemote) prepareActionExecutor() common.Executor {
 		// so we need to interpolate the expression value for uses first.
 		sar.Step.Uses = sar.RunContext.NewExpressionEvaluator(ctx).Interpolate(ctx, sar.Step.Uses)
 
-		sar.remoteAction = newRemoteAction(sar.Step.Uses)
+		forgeRegistry, err := forge.Load(sar.RunContext.Config.ForgesConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load forge registry: %w", err)
+		}
+
+		sar.remoteAction = newRemoteAction(sar.Step.Uses, forgeRegistry)
 		if sar.remoteAction == nil {
 			return fmt.Errorf("Expected format {org}/{repo}[/path]@ref. Actual '%s' Input string was not in a correct format", sar.Step.Uses)
 		}
@@ -62,37 +78,33 @@ func (sar *stepActionRemote) prepareActionExecutor() common.Executor {
 				github.Token = sar.RunContext.Config.ReplaceGheActionTokenWithGithubCom
 			}
 		}
-		token := ""
-		if isActionOnGiteaInstance(sar.remoteAction.CloneURL(sar.RunContext.Config.DefaultActionInstance)) {
- 		   token =  os.Getenv("GITEA_RUNNER_TOKEN") //(*sar.getEnv())["GITEA_RUNNER_TOKEN"]
-		   common.Logger(ctx).Debugf("Adding GITEA_RUNNER_TOKEN as Token")
-		} 
-		actionDir := fmt.Sprintf("%s/%s", sar.RunContext.ActionCacheDir(), safeFilename(sar.Step.Uses))
-		gitClone := stepActionRemoteNewCloneExecutor(git.NewGitCloneExecutorInput{
-			URL:   sar.remoteAction.CloneURL(sar.RunContext.Config.DefaultActionInstance),
-			Ref:   sar.remoteAction.Ref,
-			Dir:   actionDir,
-			Token: token, /*
-				//Note added token back
-   	
-				Shouldn't provide token when cloning actions,
-				the token comes from the instance which triggered the task,
-				however, it might be not the same instance which provides actions.
-				For GitHub, they are the same, always github.com.
-				But for Gitea, tasks triggered by a.com can clone actions from b.com.
-			*/
-		})
+		token := forgeRegistry.Token(sar.remoteAction.CloneURL(sar.RunContext.Config.DefaultActionInstance))
+		if token != "" {
+			common.Logger(ctx).Debugf("Adding forge-configured token as Token")
+		}
+		cloneURL := sar.remoteAction.CloneURL(sar.RunContext.Config.DefaultActionInstance)
+
 		var ntErr common.Executor
-		if err := gitClone(ctx); err != nil {
-			if errors.Is(err, git.ErrShortRef) {
-				return fmt.Errorf("Unable to resolve action `%s`, the provided ref `%s` is the shortened version of a commit SHA, which is not supported. Please use the full commit SHA `%s` instead",
-					sar.Step.Uses, sar.remoteAction.Ref, err.(*git.Error).Commit())
-			} else if errors.Is(err, gogit.ErrForceNeeded) { // TODO: figure out if it will be easy to shadow/alias go-git err's
-				ntErr = common.NewInfoExecutor("Non-terminating error while running 'git clone': %v", err)
-			} else {
+		actionDir, hit, err := sar.resolveActionDir(ctx, cloneURL, token)
+		if err != nil {
+			return err
+		}
+		if !hit {
+			forgeEntry, _ := forgeRegistry.Lookup(cloneURL)
+			fetched, err := sar.fetchActionArchive(ctx, cloneURL, token, actionDir, forgeEntry)
+			if err != nil {
 				return err
 			}
+			if !fetched {
+				ntErr, err = sar.cloneAction(ctx, cloneURL, token, actionDir)
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			common.Logger(ctx).Debugf("Action cache hit for '%s', skipping clone", sar.Step.Uses)
 		}
+		sar.actionDir = actionDir
 
 		remoteReader := func(ctx context.Context) actionYamlReader {
 			return func(filename string) (io.Reader, io.Closer, error) {
@@ -112,6 +124,196 @@ func (sar *stepActionRemote) prepareActionExecutor() common.Executor {
 	}
 }
 
+// cloneAction clones Step.Uses into actionDir with git.
+func (sar *stepActionRemote) cloneAction(ctx context.Context, cloneURL, token, actionDir string) (common.Executor, error) {
+	cloneDepth := sar.RunContext.Config.ActionCloneDepth
+	recursive, submoduleRecursive, submoduleDepth := sar.submoduleOptions()
+	var lfs bool
+	var sparse []string
+	if opts := sar.remoteAction.CloneOptions; opts != nil {
+		if opts.Depth != 0 {
+			cloneDepth = opts.Depth
+		}
+		switch opts.Submodules {
+		case "":
+			// not set in the fragment, leave the defaults from submoduleOptions()
+		case "recursive":
+			recursive, submoduleRecursive, submoduleDepth = true, true, 0
+		case "true":
+			recursive, submoduleRecursive, submoduleDepth = true, false, 1
+		default:
+			recursive, submoduleRecursive, submoduleDepth = false, false, 0
+		}
+		lfs = opts.LFS
+		sparse = opts.Sparse
+	}
+	gitClone := stepActionRemoteNewCloneExecutor(git.NewGitCloneExecutorInput{
+		URL:                cloneURL,
+		Ref:                sar.remoteAction.Ref,
+		Dir:                actionDir,
+		CloneDepth:         cloneDepth,
+		Recursive:          recursive,
+		SubmoduleRecursive: submoduleRecursive,
+		SubmoduleDepth:     submoduleDepth,
+		LFS:                lfs,
+		Sparse:             sparse,
+		Token:              token, /*
+			//Note added token back
+
+			Shouldn't provide token when cloning actions,
+			the token comes from the instance which triggered the task,
+			however, it might be not the same instance which provides actions.
+			For GitHub, they are the same, always github.com.
+			But for Gitea, tasks triggered by a.com can clone actions from b.com.
+		*/
+	})
+	if err := gitClone(ctx); err != nil {
+		if errors.Is(err, git.ErrShortRef) {
+			return nil, fmt.Errorf("Unable to resolve action `%s`, the provided ref `%s` is the shortened version of a commit SHA, which is not supported. Please use the full commit SHA `%s` instead",
+				sar.Step.Uses, sar.remoteAction.Ref, err.(*git.Error).Commit())
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// actionResolvedSHAFile is the sidecar file written next to an archive-fetched
+// action tree, since it has no .git directory of its own for downstream code
+// to read commit metadata from.
+const actionResolvedSHAFile = ".act-resolved-sha"
+
+// fetchActionArchive tries to materialize actionDir from the forge's tarball
+// endpoint instead of a git clone, per RunContext.Config.ActionFetchMode. It
+// returns false (not an error) whenever the caller should fall back to
+// cloneAction: the mode is "git", or (in "auto" mode) the forge has no
+// archive endpoint or the archive fetch failed for any reason, including the ref
+// not existing as a tarball (e.g. an abbreviated commit SHA forges can't
+// resolve through the archive URL).
+func (sar *stepActionRemote) fetchActionArchive(ctx context.Context, cloneURL, token, actionDir string, forgeEntry forge.Entry) (bool, error) {
+	mode := sar.RunContext.Config.ActionFetchMode
+	if mode == "" {
+		mode = "auto"
+	}
+	if mode == "git" {
+		return false, nil
+	}
+
+	hostBase := strings.TrimSuffix(cloneURL, fmt.Sprintf("/%s/%s", sar.remoteAction.Org, sar.remoteAction.Repo))
+	archiveURL := forgeEntry.ArchiveURL(hostBase, sar.remoteAction.Org, sar.remoteAction.Repo, sar.remoteAction.Ref)
+	if archiveURL == "" {
+		if mode == "archive" {
+			return false, fmt.Errorf("action fetch mode is 'archive' but forge for '%s' has no archive endpoint configured", cloneURL)
+		}
+		return false, nil
+	}
+
+	if err := os.MkdirAll(actionDir, 0o755); err != nil {
+		return false, err
+	}
+	fetch := archive.FetchTarball(archive.FetchTarballInput{URL: archiveURL, Token: token, Dir: actionDir})
+	if err := fetch(ctx); err != nil {
+		if mode == "archive" {
+			return false, err
+		}
+		common.Logger(ctx).Debugf("Fetching action archive '%s' failed (%v), falling back to git clone", archiveURL, err)
+		return false, nil
+	}
+
+	// When resolveActionDir resolved the ref, actionDir is
+	// <cacheDir>/blobs/<sha>, so the resolved SHA is just its base name - no
+	// need to resolve the ref a second time. When it couldn't, the base name
+	// is the ref itself and no sidecar is written.
+	sha := filepath.Base(actionDir)
+	if git.IsFullSHA(sha) {
+		_ = os.WriteFile(filepath.Join(actionDir, actionResolvedSHAFile), []byte(sha), 0o644)
+	}
+	return true, nil
+}
+
+// resolveActionDir resolves Step.Uses to a content-addressable blob
+// directory, consulting the on-disk ref cache first so that re-running a
+// workflow with the same `@ref` can be served without touching the network.
+// The returned bool reports whether the blob directory already existed
+// (i.e. the caller can skip cloning).
+func (sar *stepActionRemote) resolveActionDir(ctx context.Context, cloneURL, token string) (string, bool, error) {
+	cacheDir := sar.RunContext.ActionCacheDir()
+	refFile := filepath.Join(cacheDir, "refs", safeFilename(sar.Step.Uses))
+	ref := sar.remoteAction.Ref
+
+	if sha, ok := readCachedRefSHA(refFile, ref, sar.RunContext.Config.ActionCacheTTL); ok {
+		blobDir := filepath.Join(cacheDir, "blobs", sha)
+		if _, err := os.Stat(blobDir); err == nil {
+			return blobDir, true, nil
+		}
+	}
+
+	sha, err := git.ResolveRef(ctx, cloneURL, ref, token)
+	if err != nil {
+		// Resolving the ref ahead of time is a cache optimization, not a
+		// hard requirement - fall back to letting the clone itself
+		// resolve (and fail on) the ref.
+		return filepath.Join(cacheDir, "blobs", safeFilename(ref)), false, nil
+	}
+
+	blobDir := filepath.Join(cacheDir, "blobs", sha)
+	hit := false
+	if _, err := os.Stat(blobDir); err == nil {
+		hit = true
+	}
+	if err := writeCachedRefSHA(refFile, sha); err != nil {
+		return "", false, err
+	}
+	return blobDir, hit, nil
+}
+
+// readCachedRefSHA returns the previously resolved SHA for ref, if the ref
+// file exists and (for mutable refs like branches) hasn't expired. A ttl of
+// 0 (the default, unset) means mutable refs are always revalidated rather
+// than cached forever - callers that actually want to cache a mutable ref
+// must opt in with a positive ActionCacheTTL.
+func readCachedRefSHA(refFile, ref string, ttl time.Duration) (string, bool) {
+	info, err := os.Stat(refFile)
+	if err != nil {
+		return "", false
+	}
+	if !git.IsFullSHA(ref) && (ttl <= 0 || time.Since(info.ModTime()) > ttl) {
+		return "", false
+	}
+	data, err := os.ReadFile(refFile)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+func writeCachedRefSHA(refFile, sha string) error {
+	if err := os.MkdirAll(filepath.Dir(refFile), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(refFile, []byte(sha), 0o644)
+}
+
+// submoduleOptions decides whether the clone of this action should also
+// fetch submodules. Remote actions are fully recursive by default;
+// actions/checkout instead honors its own `with.submodules` input,
+// mirroring GitHub's actions/checkout semantics: "recursive" also checks
+// out submodules-of-submodules, "true" only checks out the top-level
+// submodules, and anything else skips submodules entirely.
+func (sar *stepActionRemote) submoduleOptions() (recursive, submoduleRecursive bool, submoduleDepth int) {
+	if !sar.remoteAction.IsCheckout() {
+		return true, true, 0
+	}
+
+	switch strings.ToLower(sar.Step.With["submodules"]) {
+	case "recursive":
+		return true, true, 0
+	case "true":
+		return true, false, 1
+	default:
+		return false, false, 0
+	}
+}
+
 func (sar *stepActionRemote) pre() common.Executor {
 	sar.env = map[string]string{}
 
@@ -135,9 +337,7 @@ func (sar *stepActionRemote) main() common.Executor {
 				return sar.RunContext.JobContainer.CopyDir(copyToPath, sar.RunContext.Config.Workdir+string(filepath.Separator)+".", sar.RunContext.Config.UseGitIgnore)(ctx)
 			}
 
-			actionDir := fmt.Sprintf("%s/%s", sar.RunContext.ActionCacheDir(), safeFilename(sar.Step.Uses))
-
-			return sar.runAction(sar, actionDir, sar.remoteAction)(ctx)
+			return sar.runAction(sar, sar.actionDir, sar.remoteAction)(ctx)
 		}),
 	)
 }
@@ -194,8 +394,7 @@ func (sar *stepActionRemote) getActionModel() *model.Action {
 
 func (sar *stepActionRemote) getCompositeRunContext(ctx context.Context) *RunContext {
 	if sar.compositeRunContext == nil {
-		actionDir := fmt.Sprintf("%s/%s", sar.RunContext.ActionCacheDir(), safeFilename(sar.Step.Uses))
-		actionLocation := path.Join(actionDir, sar.remoteAction.Path)
+		actionLocation := path.Join(sar.actionDir, sar.remoteAction.Path)
 		_, containerActionDir := getContainerActionPaths(sar.getStepModel(), actionLocation, sar.RunContext)
 
 		sar.compositeRunContext = newCompositeRunContext(ctx, sar.RunContext, sar, containerActionDir)
@@ -224,6 +423,18 @@ type remoteAction struct {
 	Repo string
 	Path string
 	Ref  string
+
+	// CloneOptions are set when Step.Uses used the URL-fragment syntax
+	// (`#ref:subdir?depth=1&submodules=recursive`) and override the
+	// runner-wide clone defaults for this action only.
+	CloneOptions *remoteActionCloneOptions
+}
+
+type remoteActionCloneOptions struct {
+	Depth      int
+	Submodules string
+	LFS        bool
+	Sparse     []string
 }
 
 func (ra *remoteAction) CloneURL(u string) string {
@@ -245,57 +456,32 @@ func (ra *remoteAction) IsCheckout() bool {
 	return false
 }
 
-
-func isActionOnGiteaInstance(action string) bool {
-	gitInstanceURL := os.Getenv("GITEA_INSTANCE_URL")
-	return strings.HasPrefix(action, gitInstanceURL)
-}
-
-func  isJuegoInternalServerPrefix(action string) bool {
-	//Note: Patch - Hard coded for juegoserver
-	if(strings.Contains(action, "internal-git.juegostudio.net/git")){
-		return true;
-	}
-
-	return false;
-}
-
-func  hasAServicePrefix(action string) bool {
-	//Note: Patch - Hard coded for juegoserver
-	if(isJuegoInternalServerPrefix(action)){
-		return true;
+// newRemoteAction parses a `uses:` string into org/repo/path@ref, consulting
+// registry to figure out how many path segments after the host belong to the
+// forge's URL layout (e.g. a Gitea instance nesting actions under `/git/`)
+// rather than the org.
+func newRemoteAction(action string, registry *forge.Registry) *remoteAction {
+	if strings.Contains(action, "#") {
+		return parseActionFragmentURL(action)
 	}
 
-	return false;
-}
-
-
-
-func newRemoteAction(action string) *remoteAction {
 	// support http(s)://host/owner/repo@v3
 	for _, schema := range []string{"https://", "http://"} {
 		if strings.HasPrefix(action, schema) {
-			if(hasAServicePrefix(action)){
-				splits := strings.SplitN(strings.TrimPrefix(action, schema), "/", 3)
-				if len(splits) != 3 {
-					return nil
-				}
-				ret := parseAction(splits[2])
-				if ret == nil {
-					return nil
-				}
-				ret.URL = schema + splits[0] + "/" + splits[1]
-				return ret
+			rest := strings.TrimPrefix(action, schema)
+			pathDepth := 1 // the host itself
+			if entry, ok := registry.Lookup(rest); ok && entry.URLLayout == forge.LayoutNested {
+				pathDepth += entry.PathDepth
 			}
-			splits := strings.SplitN(strings.TrimPrefix(action, schema), "/", 2)
-			if len(splits) != 2 {
+			splits := strings.SplitN(rest, "/", pathDepth+1)
+			if len(splits) != pathDepth+1 {
 				return nil
 			}
-			ret := parseAction(splits[1])
+			ret := parseAction(splits[pathDepth])
 			if ret == nil {
 				return nil
 			}
-			ret.URL = schema + splits[0]
+			ret.URL = schema + strings.Join(splits[:pathDepth], "/")
 			return ret
 		}
 	}
@@ -303,6 +489,69 @@ func newRemoteAction(action string) *remoteAction {
 	return parseAction(action)
 }
 
+// parseActionFragmentURL parses the Docker build-context style syntax
+// `https://host/org/repo.git#ref:subdir?depth=1&submodules=recursive`, used
+// when a single workflow needs per-action clone options that differ from
+// the runner-wide defaults.
+func parseActionFragmentURL(action string) *remoteAction {
+	u, err := url.Parse(action)
+	if err != nil || u.Fragment == "" {
+		return nil
+	}
+
+	// url.Parse treats everything after the first '#' as the fragment,
+	// including a trailing '?...' query string - it only populates
+	// u.RawQuery from a '?' that appears *before* the '#'. So the
+	// ref:subdir?options portion has to be split by hand here.
+	fragment := u.Fragment
+	rawQuery := ""
+	if idx := strings.Index(fragment, "?"); idx >= 0 {
+		rawQuery = fragment[idx+1:]
+		fragment = fragment[:idx]
+	}
+
+	ref := fragment
+	subdir := ""
+	if idx := strings.Index(ref, ":"); idx >= 0 {
+		subdir = ref[idx+1:]
+		ref = ref[:idx]
+	}
+
+	repoPath := strings.TrimSuffix(strings.TrimPrefix(u.Path, "/"), ".git")
+	segments := strings.SplitN(repoPath, "/", 2)
+	if len(segments) != 2 || ref == "" {
+		return nil
+	}
+
+	ret := &remoteAction{
+		URL:  u.Scheme + "://" + u.Host,
+		Org:  segments[0],
+		Repo: segments[1],
+		Path: subdir,
+		Ref:  ref,
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		query = url.Values{}
+	}
+	opts := &remoteActionCloneOptions{
+		Submodules: query.Get("submodules"),
+		LFS:        query.Get("lfs") == "true",
+	}
+	if depth := query.Get("depth"); depth != "" {
+		if d, err := strconv.Atoi(depth); err == nil {
+			opts.Depth = d
+		}
+	}
+	if sparse := query.Get("sparse"); sparse != "" {
+		opts.Sparse = strings.Split(sparse, ",")
+	}
+	ret.CloneOptions = opts
+
+	return ret
+}
+
 func parseAction(action string) *remoteAction {
 	// GitHub's document[^] describes:
 	// > We strongly recommend that you include the version of
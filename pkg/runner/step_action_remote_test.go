@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadCachedRefSHA(t *testing.T) {
+	newRefFile := func(t *testing.T, mtime time.Time) string {
+		t.Helper()
+		refFile := filepath.Join(t.TempDir(), "ref")
+		if err := os.WriteFile(refFile, []byte("deadbeef"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(refFile, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		return refFile
+	}
+
+	t.Run("full SHA ref is always cached regardless of ttl", func(t *testing.T) {
+		refFile := newRefFile(t, time.Now().Add(-24*time.Hour))
+		fullSHA := "0123456789abcdef0123456789abcdef01234567"
+		if _, ok := readCachedRefSHA(refFile, fullSHA, 0); !ok {
+			t.Error("expected full SHA ref to be cached with ttl=0")
+		}
+	})
+
+	t.Run("mutable ref with zero ttl is always revalidated", func(t *testing.T) {
+		refFile := newRefFile(t, time.Now())
+		if _, ok := readCachedRefSHA(refFile, "main", 0); ok {
+			t.Error("expected mutable ref with ttl=0 to never be served from cache")
+		}
+	})
+
+	t.Run("mutable ref within a positive ttl is cached", func(t *testing.T) {
+		refFile := newRefFile(t, time.Now())
+		if _, ok := readCachedRefSHA(refFile, "main", time.Hour); !ok {
+			t.Error("expected mutable ref within ttl to be cached")
+		}
+	})
+
+	t.Run("mutable ref past a positive ttl is revalidated", func(t *testing.T) {
+		refFile := newRefFile(t, time.Now().Add(-2*time.Hour))
+		if _, ok := readCachedRefSHA(refFile, "main", time.Hour); ok {
+			t.Error("expected mutable ref past ttl to be revalidated")
+		}
+	})
+}
+
+func TestParseActionFragmentURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		wantNil    bool
+		wantOrg    string
+		wantRepo   string
+		wantPath   string
+		wantRef    string
+		wantDepth  int
+		wantSubmod string
+		wantLFS    bool
+		wantSparse []string
+	}{
+		{
+			name:       "ref subdir and options",
+			action:     "https://example.net/org/repo.git#v1:subdir?depth=1&submodules=recursive&lfs=true&sparse=a,b",
+			wantOrg:    "org",
+			wantRepo:   "repo",
+			wantPath:   "subdir",
+			wantRef:    "v1",
+			wantDepth:  1,
+			wantSubmod: "recursive",
+			wantLFS:    true,
+			wantSparse: []string{"a", "b"},
+		},
+		{
+			name:     "ref only, no subdir or options",
+			action:   "https://example.net/org/repo.git#main",
+			wantOrg:  "org",
+			wantRepo: "repo",
+			wantPath: "",
+			wantRef:  "main",
+		},
+		{
+			name:    "no fragment",
+			action:  "https://example.net/org/repo.git",
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseActionFragmentURL(tt.action)
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("expected nil, got %+v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("expected a result, got nil")
+			}
+			if got.Org != tt.wantOrg || got.Repo != tt.wantRepo || got.Path != tt.wantPath || got.Ref != tt.wantRef {
+				t.Fatalf("got %+v, want org=%s repo=%s path=%s ref=%s", got, tt.wantOrg, tt.wantRepo, tt.wantPath, tt.wantRef)
+			}
+			if tt.wantDepth == 0 && tt.wantSubmod == "" && !tt.wantLFS && tt.wantSparse == nil {
+				if got.CloneOptions != nil && (got.CloneOptions.Depth != 0 || got.CloneOptions.Submodules != "" || got.CloneOptions.LFS || len(got.CloneOptions.Sparse) != 0) {
+					t.Fatalf("expected no clone options, got %+v", got.CloneOptions)
+				}
+				return
+			}
+			if got.CloneOptions == nil {
+				t.Fatalf("expected clone options, got nil")
+			}
+			if got.CloneOptions.Depth != tt.wantDepth {
+				t.Errorf("Depth = %d, want %d", got.CloneOptions.Depth, tt.wantDepth)
+			}
+			if got.CloneOptions.Submodules != tt.wantSubmod {
+				t.Errorf("Submodules = %q, want %q", got.CloneOptions.Submodules, tt.wantSubmod)
+			}
+			if got.CloneOptions.LFS != tt.wantLFS {
+				t.Errorf("LFS = %v, want %v", got.CloneOptions.LFS, tt.wantLFS)
+			}
+			if len(got.CloneOptions.Sparse) != len(tt.wantSparse) {
+				t.Errorf("Sparse = %v, want %v", got.CloneOptions.Sparse, tt.wantSparse)
+			}
+		})
+	}
+}